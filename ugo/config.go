@@ -0,0 +1,10 @@
+package ugo
+
+// UgoConfig holds the per-session tunables a caller can set when
+// establishing a connection.
+type UgoConfig struct {
+	// CongestionControl selects which congestion controller
+	// newSentPacketHandler wires up for this session. The zero value is
+	// CongestionControlCubic.
+	CongestionControl CongestionControlAlgorithm
+}
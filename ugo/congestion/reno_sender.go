@@ -0,0 +1,195 @@
+package congestion
+
+import "time"
+
+const (
+	// nDupAckThreshold is the number of packets that must be sacked above a
+	// hole before that hole is considered lost and fast recovery begins.
+	nDupAckThreshold = 3
+	// renoSegmentSize is the segment size RenoSender grows and shrinks cwnd
+	// by, in bytes.
+	renoSegmentSize = 1350
+)
+
+// RenoSender is a classic New Reno congestion controller backed by a SACK
+// scoreboard (RFC 6675), modeled on the gvisor TCP sender. Unlike
+// CubicSender, cwnd grows by one segment per RTT in congestion avoidance
+// (rather than following CUBIC's cubic growth function) and halves, rather
+// than collapsing, on the first loss of a recovery episode.
+type RenoSender struct {
+	clock    Clock
+	rttStats *RTTStats
+
+	congestionWindow    uint32
+	slowStartThreshold  uint32
+	maxCongestionWindow uint32
+
+	// inRecovery is true from the moment a hole is declared lost until the
+	// scoreboard shows every packet up to recoveryPoint has been acked.
+	inRecovery    bool
+	recoveryPoint uint32
+
+	// highRxt is the highest packet number retransmitted during the current
+	// recovery episode, per RFC 6675 section 4.
+	highRxt uint32
+	// rescueSent tracks whether the one optimistic retransmission allowed
+	// per recovery episode, to keep the ACK clock ticking once every known
+	// hole has already been retransmitted, has already been used.
+	rescueSent bool
+
+	// sacked holds every packet number the peer has selectively acked so
+	// far. Entries are never removed, not even once the packet is acked.
+	sacked map[uint32]bool
+	// largestAcked is the highest packet number observed in any ACK range
+	// fed to UpdateSACKScoreboard.
+	largestAcked uint32
+}
+
+// NewRenoSender creates a New Reno sender with a SACK scoreboard, for use
+// wherever a CubicSender would otherwise be constructed.
+func NewRenoSender(clock Clock, rttStats *RTTStats, initialCongestionWindow, maxCongestionWindow uint32) SendAlgorithm {
+	return &RenoSender{
+		clock:               clock,
+		rttStats:            rttStats,
+		congestionWindow:    initialCongestionWindow,
+		slowStartThreshold:  maxCongestionWindow,
+		maxCongestionWindow: maxCongestionWindow,
+		sacked:              make(map[uint32]bool),
+	}
+}
+
+// OnPacketSent is called for every packet handed to the wire.
+func (r *RenoSender) OnPacketSent(sentTime time.Time, bytesInFlight uint32, packetNumber uint32, bytes uint32, isRetransmittable bool) bool {
+	return isRetransmittable
+}
+
+// UpdateSACKScoreboard records the ranges the peer has selectively acked, so
+// IsLost can declare a hole lost as soon as nDupAckThreshold packets above it
+// are known to have arrived, without waiting on the slower NACK-count path.
+func (r *RenoSender) UpdateSACKScoreboard(ackRanges []AckRange) {
+	for _, ackRange := range ackRanges {
+		for pn := ackRange.FirstPacketNumber; pn <= ackRange.LastPacketNumber; pn++ {
+			r.sacked[pn] = true
+		}
+		if ackRange.LastPacketNumber > r.largestAcked {
+			r.largestAcked = ackRange.LastPacketNumber
+		}
+	}
+}
+
+// IsLost reports whether the scoreboard has enough SACK evidence to declare
+// packetNumber lost: it has not itself been sacked, but at least
+// nDupAckThreshold higher-numbered packets have been.
+func (r *RenoSender) IsLost(packetNumber uint32) bool {
+	if r.sacked[packetNumber] {
+		return false
+	}
+
+	var sackedAbove uint32
+	for pn := packetNumber + 1; pn <= r.largestAcked; pn++ {
+		if r.sacked[pn] {
+			sackedAbove++
+		}
+	}
+	return sackedAbove >= nDupAckThreshold
+}
+
+// OnCongestionEvent adjusts cwnd for newly acked and lost packets: additive
+// increase in congestion avoidance, and entry into fast recovery (halving
+// cwnd, recording HighRxt) the first time a packet is lost in an episode.
+func (r *RenoSender) OnCongestionEvent(rttUpdated bool, bytesInFlight uint32, ackedPackets PacketVector, lostPackets PacketVector) {
+	for _, lost := range lostPackets {
+		if !r.inRecovery {
+			r.inRecovery = true
+			r.recoveryPoint = lost.Number
+			r.rescueSent = false
+			r.slowStartThreshold = maxUint32(r.congestionWindow/2, 2*renoSegmentSize)
+			r.congestionWindow = r.slowStartThreshold
+		}
+		if lost.Number > r.highRxt {
+			r.highRxt = lost.Number
+		}
+	}
+
+	for _, acked := range ackedPackets {
+		if r.inRecovery {
+			if acked.Number >= r.recoveryPoint {
+				r.inRecovery = false
+				r.highRxt = 0
+				r.rescueSent = false
+			}
+			continue
+		}
+
+		if r.congestionWindow < r.slowStartThreshold {
+			// slow start: one segment of growth per acked packet
+			r.congestionWindow += renoSegmentSize
+		} else {
+			// congestion avoidance: roughly one segment of growth per RTT
+			r.congestionWindow += renoSegmentSize * renoSegmentSize / r.congestionWindow
+		}
+	}
+
+	if r.congestionWindow > r.maxCongestionWindow {
+		r.congestionWindow = r.maxCongestionWindow
+	}
+}
+
+// MaybeRescueRetransmit returns the one optimistic tail retransmission RFC
+// 6675 allows per recovery episode, to keep the ACK clock ticking. It
+// returns outstandingHighest on the first call of a recovery episode,
+// without checking whether any hole below it is still un-retransmitted. It
+// returns 0 outside recovery, and once the rescue retransmit has already
+// been used this episode, regardless of how outstandingHighest has moved on
+// since.
+func (r *RenoSender) MaybeRescueRetransmit(outstandingHighest uint32) uint32 {
+	if !r.inRecovery || r.rescueSent {
+		return 0
+	}
+	r.rescueSent = true
+	return outstandingHighest
+}
+
+// GetCongestionWindow returns the current congestion window, in bytes.
+func (r *RenoSender) GetCongestionWindow() uint32 {
+	return r.congestionWindow
+}
+
+// InSlowStart reports whether cwnd is still growing exponentially, i.e.
+// whether it is below the slow start threshold.
+func (r *RenoSender) InSlowStart() bool {
+	return r.congestionWindow < r.slowStartThreshold
+}
+
+// RetransmissionDelay returns the current RTO, based on the smoothed RTT.
+func (r *RenoSender) RetransmissionDelay() time.Duration {
+	if r.rttStats.SmoothedRTT() == 0 {
+		return 0
+	}
+	return r.rttStats.SmoothedRTT() * 2
+}
+
+// HandleRTOExpired collapses cwnd to a single segment and restarts slow
+// start, as RTO recovery is far more conservative than fast recovery.
+func (r *RenoSender) HandleRTOExpired() {
+	r.slowStartThreshold = maxUint32(r.congestionWindow/2, 2*renoSegmentSize)
+	r.congestionWindow = renoSegmentSize
+	r.inRecovery = false
+	r.highRxt = 0
+	r.rescueSent = false
+}
+
+// OnRetransmissionTimeout is the SendAlgorithm hook invoked on RTO; it just
+// delegates to HandleRTOExpired when the timeout actually fired.
+func (r *RenoSender) OnRetransmissionTimeout(packetsRetransmitted bool) {
+	if packetsRetransmitted {
+		r.HandleRTOExpired()
+	}
+}
+
+func maxUint32(a, b uint32) uint32 {
+	if a > b {
+		return a
+	}
+	return b
+}
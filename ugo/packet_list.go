@@ -0,0 +1,96 @@
+package ugo
+
+// PacketElement is an entry in a PacketList. It holds the *Packet itself in
+// Value, plus the list pointers needed to walk in the order packets were sent.
+type PacketElement struct {
+	next, prev *PacketElement
+	list       *PacketList
+
+	Value *Packet
+}
+
+// Next returns the next list element, or nil if e is the last element.
+func (e *PacketElement) Next() *PacketElement {
+	if p := e.next; e.list != nil && p != &e.list.root {
+		return p
+	}
+	return nil
+}
+
+// Prev returns the previous list element, or nil if e is the first element.
+func (e *PacketElement) Prev() *PacketElement {
+	if p := e.prev; e.list != nil && p != &e.list.root {
+		return p
+	}
+	return nil
+}
+
+// PacketList is a doubly linked list of *Packet, kept in the order packets
+// were sent. sentPacketHandler keeps a PacketElement per packet number in a
+// side index, so walking in packet-number order is O(k) and removing a
+// packet is O(1), instead of the O(n) scans a map forces.
+type PacketList struct {
+	root PacketElement // sentinel list element, only &root, root.prev, and root.next are used
+	len  int
+}
+
+// NewPacketList returns an initialized, empty PacketList.
+func NewPacketList() *PacketList {
+	l := &PacketList{}
+	l.root.next = &l.root
+	l.root.prev = &l.root
+	return l
+}
+
+// Len returns the number of elements in the list.
+func (l *PacketList) Len() int { return l.len }
+
+// Front returns the oldest (lowest packet number, in send order) element of
+// the list, or nil if the list is empty.
+func (l *PacketList) Front() *PacketElement {
+	if l.len == 0 {
+		return nil
+	}
+	return l.root.next
+}
+
+// Back returns the newest element of the list, or nil if the list is empty.
+func (l *PacketList) Back() *PacketElement {
+	if l.len == 0 {
+		return nil
+	}
+	return l.root.prev
+}
+
+// insert inserts e after at, increments l.len, and returns e.
+func (l *PacketList) insert(e, at *PacketElement) *PacketElement {
+	n := at.next
+	at.next = e
+	e.prev = at
+	e.next = n
+	n.prev = e
+	e.list = l
+	l.len++
+	return e
+}
+
+// PushBack inserts a new element with value v at the back of the list and
+// returns it.
+func (l *PacketList) PushBack(v *Packet) *PacketElement {
+	return l.insert(&PacketElement{Value: v}, l.root.prev)
+}
+
+// Remove removes e from the list and returns its Value. It is a no-op, and
+// returns nil, if e is not an element of l.
+func (l *PacketList) Remove(e *PacketElement) *Packet {
+	if e.list != l {
+		return nil
+	}
+	e.prev.next = e.next
+	e.next.prev = e.prev
+	e.next = nil // avoid memory leaks
+	e.prev = nil
+	e.list = nil
+	l.len--
+	return e.Value
+}
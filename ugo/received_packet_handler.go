@@ -0,0 +1,106 @@
+package ugo
+
+import (
+	"time"
+
+	"./congestion"
+	"./utils"
+)
+
+const (
+	// ackElicitingThreshold forces an ACK out once this many ack-eliciting
+	// packets have arrived without one being sent, so the sender's
+	// congestion/loss-detection state doesn't go stale on a bulk transfer.
+	ackElicitingThreshold = 2
+	// minAckSendDelay is the floor on how long a non-reordering ACK can be
+	// coalesced for, so a zero or tiny SmoothedRTT can't make ACKs fire
+	// immediately for every packet.
+	minAckSendDelay = time.Millisecond
+)
+
+// receivedPacketTracker mirrors sentPacketHandler on the receive side: it
+// records which packet numbers have arrived, and decides whether an ACK
+// should go out immediately or be coalesced behind an alarm, rather than
+// sending one ACK per received packet.
+type receivedPacketTracker struct {
+	largestObserved             uint32
+	largestObservedReceivedTime time.Time
+
+	receivedPacketHistory receivedPacketHistory
+
+	ackElicitingPacketsReceivedSinceLastAck int
+	packetsReceivedSinceLastAck             int
+
+	// ackAlarm is the time at which GetAckFrame should be called, or the
+	// zero Time if nothing is owed to the peer right now.
+	ackAlarm time.Time
+
+	rttStats *congestion.RTTStats
+}
+
+// newReceivedPacketTracker creates a new receivedPacketTracker.
+func newReceivedPacketTracker(rttStats *congestion.RTTStats) *receivedPacketTracker {
+	return &receivedPacketTracker{rttStats: rttStats}
+}
+
+// ReceivedPacket registers a newly received packet and updates ackAlarm:
+// an ACK is scheduled immediately if ackElicitingThreshold has been reached
+// or the packet arrived out of order, otherwise it's coalesced for up to
+// maxAckDelay.
+func (t *receivedPacketTracker) ReceivedPacket(packetNumber uint32, rcvTime time.Time, ackEliciting bool) {
+	isMissingPackets := t.largestObserved != 0 && packetNumber != t.largestObserved+1
+
+	t.receivedPacketHistory.AddPacket(packetNumber)
+
+	if packetNumber > t.largestObserved {
+		t.largestObserved = packetNumber
+		t.largestObservedReceivedTime = rcvTime
+	}
+
+	if !ackEliciting {
+		return
+	}
+
+	t.ackElicitingPacketsReceivedSinceLastAck++
+	t.packetsReceivedSinceLastAck++
+
+	if t.ackElicitingPacketsReceivedSinceLastAck >= ackElicitingThreshold || isMissingPackets {
+		t.ackAlarm = rcvTime
+		return
+	}
+
+	if t.ackAlarm.IsZero() {
+		delay := utils.MaxDuration(t.rttStats.SmoothedRTT()/4, minAckSendDelay)
+		if delay > maxAckDelay {
+			delay = maxAckDelay
+		}
+		t.ackAlarm = rcvTime.Add(delay)
+	}
+}
+
+// GetAlarmTimeout returns the time at which the connection's run loop
+// should call GetAckFrame, or the zero Time if no ACK is pending.
+func (t *receivedPacketTracker) GetAlarmTimeout() time.Time {
+	return t.ackAlarm
+}
+
+// GetAckFrame returns the ACK frame to send, or nil if nothing is owed to
+// the peer. Calling it resets the coalescing state.
+func (t *receivedPacketTracker) GetAckFrame() *AckFrame {
+	if t.packetsReceivedSinceLastAck == 0 {
+		return nil
+	}
+
+	ackFrame := &AckFrame{
+		LargestAcked:   t.largestObserved,
+		LargestInOrder: t.receivedPacketHistory.LargestInOrder(),
+		DelayTime:      time.Now().Sub(t.largestObservedReceivedTime),
+		AckRanges:      t.receivedPacketHistory.GetAckRanges(),
+	}
+
+	t.ackElicitingPacketsReceivedSinceLastAck = 0
+	t.packetsReceivedSinceLastAck = 0
+	t.ackAlarm = time.Time{}
+
+	return ackFrame
+}
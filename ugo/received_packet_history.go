@@ -0,0 +1,62 @@
+package ugo
+
+import "sort"
+
+// receivedPacketHistory tracks which packet numbers have been received as a
+// set of contiguous ranges (kept sorted ascending by FirstPacketNumber), so
+// that GetAckRanges can hand back AckFrame.AckRanges without walking every
+// individual packet number.
+type receivedPacketHistory struct {
+	ranges []AckRange
+}
+
+// AddPacket records packetNumber as received, merging it into an existing
+// range, or creating a new one, as needed.
+func (h *receivedPacketHistory) AddPacket(packetNumber uint32) {
+	i := sort.Search(len(h.ranges), func(i int) bool {
+		return h.ranges[i].LastPacketNumber >= packetNumber
+	})
+
+	if i < len(h.ranges) && packetNumber >= h.ranges[i].FirstPacketNumber {
+		return // already recorded
+	}
+
+	h.ranges = append(h.ranges, AckRange{})
+	copy(h.ranges[i+1:], h.ranges[i:])
+	h.ranges[i] = AckRange{FirstPacketNumber: packetNumber, LastPacketNumber: packetNumber}
+
+	if i > 0 && h.ranges[i-1].LastPacketNumber+1 == h.ranges[i].FirstPacketNumber {
+		h.ranges[i-1].LastPacketNumber = h.ranges[i].LastPacketNumber
+		h.ranges = append(h.ranges[:i], h.ranges[i+1:]...)
+		i--
+	}
+
+	if i+1 < len(h.ranges) && h.ranges[i].LastPacketNumber+1 == h.ranges[i+1].FirstPacketNumber {
+		h.ranges[i].LastPacketNumber = h.ranges[i+1].LastPacketNumber
+		h.ranges = append(h.ranges[:i+1], h.ranges[i+2:]...)
+	}
+}
+
+// LargestInOrder returns the largest packet number received as part of an
+// unbroken run starting at packet number 1, or 0 if even the first packet
+// is still missing.
+func (h *receivedPacketHistory) LargestInOrder() uint32 {
+	if len(h.ranges) == 0 || h.ranges[0].FirstPacketNumber > 1 {
+		return 0
+	}
+	return h.ranges[0].LastPacketNumber
+}
+
+// GetAckRanges returns the received ranges in the order AckFrame.AckRanges
+// expects them on the wire: highest range first, lowest range last.
+func (h *receivedPacketHistory) GetAckRanges() []AckRange {
+	if len(h.ranges) == 0 {
+		return nil
+	}
+
+	ackRanges := make([]AckRange, len(h.ranges))
+	for i, r := range h.ranges {
+		ackRanges[len(h.ranges)-1-i] = r
+	}
+	return ackRanges
+}
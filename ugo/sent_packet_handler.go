@@ -18,10 +18,35 @@ var (
 	// ErrTooManyTrackedSentPackets occurs when the sentPacketHandler has to keep track of too many packets
 	ErrTooManyTrackedSentPackets = errors.New("Too many outstanding non-acked and non-retransmitted packets")
 	errAckForUnsentPacket        = errors.New("Received ACK for an unsent package")
+	// ErrAckForSkippedPacket occurs when the peer acks a packet number that
+	// was never actually sent, only reserved as a skipped sentinel. This is
+	// a cheap signal that the ACK was tampered with or replayed.
+	ErrAckForSkippedPacket = errors.New("SentPacketHandler: Received an ACK for a skipped packet number")
 )
 
 var errDuplicatePacketNumber = errors.New("Packet number already exists in Packet History")
 
+const (
+	// reorderingThreshold is the number of packets that can arrive out of
+	// order before a packet is declared lost, matching the NACK count used
+	// by the fast-retransmit path below.
+	reorderingThreshold = 3
+	// timeReorderingFraction is added on top of the RTT before a packet is
+	// considered lost purely on account of time, as used by the QUIC loss
+	// detection draft.
+	timeReorderingFraction = 1.0 / 8
+	// maxAckDelay is the largest delay we expect a peer to impose before
+	// acknowledging a packet. It is added to the tail loss probe timeout,
+	// and caps how long receivedPacketTracker coalesces an ACK.
+	maxAckDelay = 25 * time.Millisecond
+	// minTLPTimeout is the floor for the tail loss probe timer, so that a
+	// very small RTT can't make the TLP fire unreasonably fast.
+	minTLPTimeout = 10 * time.Millisecond
+	// maxTLPs is the number of tail loss probes sent before falling back to
+	// a full RTO.
+	maxTLPs = 2
+)
+
 type sentPacketHandler struct {
 	lastSentPacketNumber uint32
 	lastSentPacketTime   time.Time
@@ -30,7 +55,33 @@ type sentPacketHandler struct {
 
 	largestReceivedPacketWithAck uint32
 
-	packetHistory      map[uint32]*Packet
+	// lossTime is the time at which the next packet will be declared lost
+	// purely because too much time has passed since it was sent, as opposed
+	// to being declared lost because of the reorderingThreshold. It is the
+	// zero Time if no packet is currently a time-loss candidate.
+	lossTime time.Time
+
+	// lastSentRetransmittablePacketTime is the send time of the most recent
+	// packet carrying retransmittable data, used to schedule the TLP timer.
+	lastSentRetransmittablePacketTime time.Time
+	// tlpCount is the number of tail loss probes sent since the last RTO.
+	// At most maxTLPs are sent before falling back to maybeQueuePacketsRTO.
+	tlpCount int
+	// pingNeeded is set by maybeSendTLP when a probe is due but there is no
+	// retransmittable packet outstanding to reuse, so the caller must
+	// synthesize and send a fresh packet containing only a PING frame.
+	pingNeeded bool
+
+	// nextPacketSendTime is the earliest time the pacer allows the next
+	// packet to go out, so CongestionAllowsSending doesn't permit a full
+	// cwnd of packets to burst onto the wire back-to-back.
+	nextPacketSendTime time.Time
+
+	// packetHistory holds every outstanding sent packet, in the order it was
+	// sent. packetHistoryIndex mirrors it for O(1) lookup/removal by packet
+	// number; the two must always be kept in sync.
+	packetHistory      *PacketList
+	packetHistoryIndex map[uint32]*PacketElement
 	stopWaitingManager stopWaitingManager
 
 	retransmissionQueue []*Packet
@@ -44,29 +95,112 @@ type sentPacketHandler struct {
 	totalAcked uint32
 }
 
-// NewSentPacketHandler creates a new sentPacketHandler
-func newSentPacketHandler() *sentPacketHandler {
+// CongestionControlAlgorithm selects which congestion.SendAlgorithm
+// newSentPacketHandler wires up for a session. It's the type of
+// UgoConfig.CongestionControl, so callers pick the algorithm by setting that
+// field rather than calling newSentPacketHandler directly.
+type CongestionControlAlgorithm int
+
+const (
+	// CongestionControlCubic is the default, matching chromium's choice.
+	CongestionControlCubic CongestionControlAlgorithm = iota
+	// CongestionControlRenoSACK is classic New Reno with a SACK scoreboard.
+	CongestionControlRenoSACK
+)
+
+// sackScoreboard is implemented by congestion controllers that keep a SACK
+// scoreboard (currently only congestion.RenoSender). sentPacketHandler
+// feeds it ACK ranges and consults IsLost when deciding whether a packet
+// should be retransmitted, without needing to know the concrete type.
+type sackScoreboard interface {
+	UpdateSACKScoreboard(ackRanges []AckRange)
+	IsLost(packetNumber uint32) bool
+}
+
+// sackRescuer is implemented by congestion controllers that support RFC
+// 6675's optimistic "rescue" retransmission at the tail of the scoreboard
+// (currently only congestion.RenoSender), used to keep the ACK clock
+// ticking once every known hole has already been retransmitted.
+type sackRescuer interface {
+	MaybeRescueRetransmit(outstandingHighest uint32) uint32
+}
+
+// slowStartReporter is implemented by congestion controllers that can say
+// whether they're still in slow start, so the pacer can use a more
+// aggressive gain while cwnd is growing exponentially.
+type slowStartReporter interface {
+	InSlowStart() bool
+}
+
+const (
+	// slowStartPacingGain inflates the pacing rate while cwnd is still
+	// growing exponentially, so pacing doesn't itself become the bottleneck
+	// during slow start.
+	slowStartPacingGain = 1.25
+	// pacingGain is used once congestion avoidance has been reached.
+	pacingGain = 1.0
+)
+
+// newSentPacketHandler creates a new sentPacketHandler, wiring up the
+// congestion controller config selects (or CongestionControlCubic if config
+// is nil).
+func newSentPacketHandler(config *UgoConfig) *sentPacketHandler {
 	rttStats := &congestion.RTTStats{}
 
-	congestion := congestion.NewCubicSender(
-		congestion.DefaultClock{},
-		rttStats,
-		false, /* don't use reno since chromium doesn't (why?) */
-		InitialCongestionWindow,
-		DefaultMaxCongestionWindow,
-	)
+	var cc CongestionControlAlgorithm
+	if config != nil {
+		cc = config.CongestionControl
+	}
+
+	var sendAlgorithm congestion.SendAlgorithm
+	switch cc {
+	case CongestionControlRenoSACK:
+		sendAlgorithm = congestion.NewRenoSender(
+			congestion.DefaultClock{},
+			rttStats,
+			InitialCongestionWindow,
+			DefaultMaxCongestionWindow,
+		)
+	default:
+		sendAlgorithm = congestion.NewCubicSender(
+			congestion.DefaultClock{},
+			rttStats,
+			false, /* don't use reno since chromium doesn't (why?) */
+			InitialCongestionWindow,
+			DefaultMaxCongestionWindow,
+		)
+	}
 
 	return &sentPacketHandler{
-		packetHistory:      make(map[uint32]*Packet),
+		packetHistory:      NewPacketList(),
+		packetHistoryIndex: make(map[uint32]*PacketElement),
 		stopWaitingManager: stopWaitingManager{},
 		rttStats:           rttStats,
-		congestion:         congestion,
+		congestion:         sendAlgorithm,
+	}
+}
+
+// removeFromHistory removes packetNumber from both packetHistory and
+// packetHistoryIndex, keeping the two in sync.
+func (h *sentPacketHandler) removeFromHistory(packetNumber uint32) {
+	if el, ok := h.packetHistoryIndex[packetNumber]; ok {
+		h.packetHistory.Remove(el)
+		delete(h.packetHistoryIndex, packetNumber)
 	}
 }
 
-func (h *sentPacketHandler) ackPacket(packetNumber uint32) *Packet {
-	packet, ok := h.packetHistory[packetNumber]
-	if ok && !packet.Retransmitted {
+func (h *sentPacketHandler) ackPacket(packetNumber uint32) (*Packet, error) {
+	el, ok := h.packetHistoryIndex[packetNumber]
+	if !ok {
+		return nil, nil
+	}
+	packet := el.Value
+
+	if packet.skippedPacket {
+		return packet, ErrAckForSkippedPacket
+	}
+
+	if !packet.Retransmitted {
 		/*
 		* if the packet is marked as retransmitted,
 		* it means this packet is queued for retransmission,
@@ -90,22 +224,38 @@ func (h *sentPacketHandler) ackPacket(packetNumber uint32) *Packet {
 		h.stopWaitingManager.largestLeastUnackedSent = h.largestInOrderAcked + 1
 	}
 
-	delete(h.packetHistory, packetNumber)
+	h.removeFromHistory(packetNumber)
 
-	return packet
+	return packet, nil
 }
 
 func (h *sentPacketHandler) nackPacket(packetNumber uint32) (*Packet, error) {
-	packet, ok := h.packetHistory[packetNumber]
+	el, ok := h.packetHistoryIndex[packetNumber]
 	// This means that the packet has already been retransmitted, do nothing.
 	// We're probably only receiving another NACK for this packet because the
 	// retransmission has not yet arrived at the client.
 	if !ok {
 		return nil, nil
 	}
+	packet := el.Value
+
+	// A skipped packet number is never actually sent, so it is never
+	// "missing" in any meaningful sense: every ordinary ACK that hasn't
+	// caught up to it yet will NACK it forever. That's expected and not a
+	// loss signal; only ackPacket, where a skipped number would mean the
+	// peer claims to have received it, treats this as tampering.
+	if packet.skippedPacket {
+		return nil, nil
+	}
 
 	packet.MissingReports++
 
+	if sb, ok := h.congestion.(sackScoreboard); ok && sb.IsLost(packetNumber) && !packet.Retransmitted {
+		log.Printf("SACK scoreboard marked packet %d lost, Missing count %d", packet.PacketNumber, packet.MissingReports)
+		h.queuePacketForRetransmission(packet)
+		return packet, nil
+	}
+
 	if packet.MissingReports > 3 && !packet.Retransmitted {
 		log.Printf("fast retransimition packet %d, Missing count %d", packet.PacketNumber, packet.MissingReports)
 		h.queuePacketForRetransmission(packet) // fast retransmition
@@ -125,7 +275,7 @@ func (h *sentPacketHandler) queuePacketForRetransmission(packet *Packet) {
 	if packet.PacketNumber == h.largestInOrderAcked+1 {
 		h.largestInOrderAcked++
 		for i := h.largestInOrderAcked + 1; i <= h.largestAcked; i++ {
-			_, ok := h.packetHistory[uint32(i)]
+			_, ok := h.packetHistoryIndex[uint32(i)]
 			if !ok {
 				h.largestInOrderAcked = i
 			} else {
@@ -141,7 +291,7 @@ func (h *sentPacketHandler) queuePacketForRetransmission(packet *Packet) {
 }
 
 func (h *sentPacketHandler) SentPacket(packet *Packet) error {
-	_, ok := h.packetHistory[packet.PacketNumber]
+	_, ok := h.packetHistoryIndex[packet.PacketNumber]
 	if ok {
 		return errDuplicatePacketNumber
 	}
@@ -157,7 +307,8 @@ func (h *sentPacketHandler) SentPacket(packet *Packet) error {
 	if packet.flag != 0x80 {
 		h.totalSend += packet.Length
 		h.bytesInFlight += packet.Length
-		h.packetHistory[packet.PacketNumber] = packet
+		h.packetHistoryIndex[packet.PacketNumber] = h.packetHistory.PushBack(packet)
+		h.lastSentRetransmittablePacketTime = now
 
 		h.congestion.OnPacketSent(
 			time.Now(),
@@ -166,10 +317,57 @@ func (h *sentPacketHandler) SentPacket(packet *Packet) error {
 			packet.Length,
 			true, /* TODO: is retransmittable */
 		)
+
+		h.updatePacingRate(now, packet.Length)
 	}
 	return nil
 }
 
+// updatePacingRate schedules nextPacketSendTime so that packets are spread
+// out over roughly an RTT's worth of the congestion window, instead of the
+// microburst CongestionAllowsSending would otherwise permit up to cwnd.
+func (h *sentPacketHandler) updatePacingRate(now time.Time, packetLength uint32) {
+	srtt := h.rttStats.SmoothedRTT()
+	if srtt <= 0 {
+		srtt = DefaultInitialRTT
+	}
+
+	gain := pacingGain
+	if ss, ok := h.congestion.(slowStartReporter); ok && ss.InSlowStart() {
+		gain = slowStartPacingGain
+	}
+
+	pacingRate := gain * float64(h.congestion.GetCongestionWindow()) / srtt.Seconds()
+	if pacingRate <= 0 {
+		return
+	}
+
+	sendDuration := time.Duration(float64(packetLength) / pacingRate * float64(time.Second))
+
+	start := now
+	if h.nextPacketSendTime.After(start) {
+		start = h.nextPacketSendTime
+	}
+	h.nextPacketSendTime = start.Add(sendDuration)
+}
+
+// TimeUntilSend returns how long the caller must still wait before the
+// pacer allows the next packet to be sent.
+func (h *sentPacketHandler) TimeUntilSend() time.Duration {
+	return utils.MaxDuration(0, h.nextPacketSendTime.Sub(time.Now()))
+}
+
+// SkipPacketNumber inserts a sentinel entry for a packet number the sender
+// deliberately never sends data on. An ACK that later covers this number
+// cannot correspond to anything we actually sent, so ReceivedAck treats it
+// as a tamper/replay signal rather than a legitimate ACK.
+func (h *sentPacketHandler) SkipPacketNumber(packetNumber uint32) {
+	h.packetHistoryIndex[packetNumber] = h.packetHistory.PushBack(&Packet{
+		PacketNumber:  packetNumber,
+		skippedPacket: true,
+	})
+}
+
 func (h *sentPacketHandler) ReceivedAck(ackFrame *AckFrame, withPacketNumber uint32) error {
 	if ackFrame.LargestAcked > h.lastSentPacketNumber {
 		return errAckForUnsentPacket
@@ -198,8 +396,9 @@ func (h *sentPacketHandler) ReceivedAck(ackFrame *AckFrame, withPacketNumber uin
 
 	h.largestAcked = ackFrame.LargestAcked
 
-	packet, ok := h.packetHistory[h.largestAcked]
+	el, ok := h.packetHistoryIndex[h.largestAcked]
 	if ok {
+		packet := el.Value
 		// Update the RTT
 		timeDelta := time.Now().Sub(packet.SendTime)
 		// TODO: Don't always update RTT
@@ -209,6 +408,10 @@ func (h *sentPacketHandler) ReceivedAck(ackFrame *AckFrame, withPacketNumber uin
 
 	}
 
+	if sb, ok := h.congestion.(sackScoreboard); ok {
+		sb.UpdateSACKScoreboard(ackFrame.AckRanges)
+	}
+
 	var ackedPackets congestion.PacketVector
 	var lostPackets congestion.PacketVector
 
@@ -236,7 +439,10 @@ func (h *sentPacketHandler) ReceivedAck(ackFrame *AckFrame, withPacketNumber uin
 			}
 
 			if i >= ackRange.FirstPacketNumber { // packet i contained in ACK range
-				p := h.ackPacket(i)
+				p, err := h.ackPacket(i)
+				if err != nil {
+					return err
+				}
 				if p != nil {
 					ackedPackets = append(ackedPackets, congestion.PacketInfo{Number: p.PacketNumber, Length: p.Length})
 				}
@@ -250,13 +456,25 @@ func (h *sentPacketHandler) ReceivedAck(ackFrame *AckFrame, withPacketNumber uin
 				}
 			}
 		} else {
-			p := h.ackPacket(i)
+			p, err := h.ackPacket(i)
+			if err != nil {
+				return err
+			}
 			if p != nil {
 				ackedPackets = append(ackedPackets, congestion.PacketInfo{Number: p.PacketNumber, Length: p.Length})
 			}
 		}
 	}
 
+	if sr, ok := h.congestion.(sackRescuer); ok {
+		if rescuePacketNumber := sr.MaybeRescueRetransmit(h.lastSentPacketNumber); rescuePacketNumber != 0 {
+			if el, ok := h.packetHistoryIndex[rescuePacketNumber]; ok && !el.Value.Retransmitted && !el.Value.skippedPacket {
+				log.Printf("SACK rescue retransmit of packet %d", rescuePacketNumber)
+				h.queuePacketForRetransmission(el.Value)
+			}
+		}
+	}
+
 	log.Printf("largest in order send %d, ack in order %d", h.largestInOrderAcked, ackFrame.LargestInOrder)
 
 	h.congestion.OnCongestionEvent(
@@ -266,15 +484,90 @@ func (h *sentPacketHandler) ReceivedAck(ackFrame *AckFrame, withPacketNumber uin
 		lostPackets,
 	)
 
-	log.Printf("sent %d, acked %d, history size: %d", h.totalSend, h.totalAcked, len(h.packetHistory))
+	log.Printf("sent %d, acked %d, history size: %d", h.totalSend, h.totalAcked, h.packetHistory.Len())
+
+	h.detectLostPackets()
 
 	return nil
 }
 
+// detectLostPackets applies QUIC-style time-threshold loss detection on top
+// of the NACK-count based path above. A packet is declared lost if either
+// it is reorderingThreshold packets behind largestAcked, or if more time
+// than (1+timeReorderingFraction)*max(SmoothedRTT, LatestRTT) has passed
+// since it was sent. Packets that are neither lost nor acked yet advance
+// lossTime to the earliest point at which they would become time-lost.
+func (h *sentPacketHandler) detectLostPackets() {
+	h.lossTime = time.Time{}
+
+	maxRTT := utils.MaxDuration(h.rttStats.SmoothedRTT(), h.rttStats.LatestRTT())
+	delayUntilLost := time.Duration(float64(maxRTT) * (1 + timeReorderingFraction))
+
+	var lostPackets congestion.PacketVector
+
+	now := time.Now()
+	for el := h.packetHistory.Front(); el != nil; el = el.Next() {
+		packet := el.Value
+		// packetHistory is in send order, so packet numbers only increase
+		// from here on; nothing past largestAcked can be lost yet.
+		if packet.PacketNumber >= h.largestAcked {
+			break
+		}
+		if packet.Retransmitted || packet.skippedPacket {
+			continue
+		}
+
+		packetsAfter := h.largestAcked - packet.PacketNumber
+		timeSinceSent := now.Sub(packet.SendTime)
+
+		if packetsAfter >= reorderingThreshold || timeSinceSent > delayUntilLost {
+			log.Printf("time-threshold loss detected, packet %d, %d packets after largest acked, sent %s ago", packet.PacketNumber, packetsAfter, timeSinceSent)
+			h.queuePacketForRetransmission(packet)
+			lostPackets = append(lostPackets, congestion.PacketInfo{Number: packet.PacketNumber, Length: packet.Length})
+			continue
+		}
+
+		lossDeadline := packet.SendTime.Add(delayUntilLost)
+		if h.lossTime.IsZero() || lossDeadline.Before(h.lossTime) {
+			h.lossTime = lossDeadline
+		}
+	}
+
+	if len(lostPackets) > 0 {
+		h.congestion.OnCongestionEvent(false, h.BytesInFlight(), nil, lostPackets)
+	}
+}
+
+// GetAlarmTimeout returns the time at which the connection's run loop should
+// next call OnAlarm: either the time-threshold loss deadline computed by
+// detectLostPackets, or the RTO, whichever comes first.
+func (h *sentPacketHandler) GetAlarmTimeout() time.Time {
+	rto := h.TimeOfFirstRTO()
+	if h.lossTime.IsZero() {
+		return rto
+	}
+	if rto.IsZero() || h.lossTime.Before(rto) {
+		return h.lossTime
+	}
+	return rto
+}
+
+// OnAlarm is called by the connection's run loop when the timer returned by
+// GetAlarmTimeout fires. If a time-threshold loss was pending, it re-runs
+// detection; otherwise this is an RTO and falls back to maybeQueuePacketsRTO.
+func (h *sentPacketHandler) OnAlarm() {
+	if !h.lossTime.IsZero() {
+		h.detectLostPackets()
+		return
+	}
+	h.maybeQueuePacketsRTO()
+}
+
 // ProbablyHasPacketForRetransmission returns if there is a packet queued for retransmission
 // There is one case where it gets the answer wrong:
 // if a packet has already been queued for retransmission, but a belated ACK is received for this packet, this function will return true, although the packet will not be returend for retransmission by DequeuePacketForRetransmission()
 func (h *sentPacketHandler) ProbablyHasPacketForRetransmission() bool {
+	h.maybeSendTLP()
 	h.maybeQueuePacketsRTO()
 
 	return len(h.retransmissionQueue) > 0
@@ -293,12 +586,12 @@ func (h *sentPacketHandler) DequeuePacketForRetransmission() (packet *Packet) {
 
 		// this happens if a belated ACK arrives for this packet
 		// no need to retransmit it
-		_, ok := h.packetHistory[packet.PacketNumber]
+		_, ok := h.packetHistoryIndex[packet.PacketNumber]
 		if !ok {
 			continue
 		}
 
-		delete(h.packetHistory, packet.PacketNumber)
+		h.removeFromHistory(packet.PacketNumber)
 		return packet
 	}
 
@@ -318,13 +611,16 @@ func (h *sentPacketHandler) GetStopWaitingFrame() uint32 {
 }
 
 func (h *sentPacketHandler) CongestionAllowsSending() bool {
+	if time.Now().Before(h.nextPacketSendTime) {
+		return false
+	}
 	return h.BytesInFlight() <= h.congestion.GetCongestionWindow()
 }
 
 func (h *sentPacketHandler) CheckForError() error {
-	length := len(h.retransmissionQueue) + len(h.packetHistory)
+	length := len(h.retransmissionQueue) + h.packetHistory.Len()
 	if length > 2000 {
-		log.Printf("retransmissionQueue size: %d, history size: %d", len(h.retransmissionQueue), len(h.packetHistory))
+		log.Printf("retransmissionQueue size: %d, history size: %d", len(h.retransmissionQueue), h.packetHistory.Len())
 		return ErrTooManyTrackedSentPackets
 	}
 	return nil
@@ -336,8 +632,9 @@ func (h *sentPacketHandler) maybeQueuePacketsRTO() {
 	}
 
 	for p := h.largestInOrderAcked + 1; p <= h.lastSentPacketNumber; p++ {
-		packet := h.packetHistory[p]
-		if packet != nil && !packet.Retransmitted {
+		el := h.packetHistoryIndex[p]
+		if el != nil && !el.Value.Retransmitted && !el.Value.skippedPacket {
+			packet := el.Value
 			packetsLost := congestion.PacketVector{congestion.PacketInfo{
 				Number: packet.PacketNumber,
 				Length: packet.Length,
@@ -346,11 +643,78 @@ func (h *sentPacketHandler) maybeQueuePacketsRTO() {
 			h.congestion.OnRetransmissionTimeout(true)
 			log.Printf("timeout retransmission, packet %d, send time:%s, now: %s", packet.PacketNumber, packet.SendTime.String(), time.Now().String())
 			h.queuePacketForRetransmission(packet)
+			h.tlpCount = 0
 			return
 		}
 	}
 }
 
+// GetTLPTimeout returns the time at which a tail loss probe becomes due:
+// the send time of the most recent retransmittable packet plus
+// max(2*SmoothedRTT, 1.5*SmoothedRTT+maxAckDelay), with a floor of
+// minTLPTimeout. It returns the zero Time if nothing retransmittable has
+// been sent yet.
+func (h *sentPacketHandler) GetTLPTimeout() time.Time {
+	if h.lastSentRetransmittablePacketTime.IsZero() {
+		return time.Time{}
+	}
+
+	srtt := h.rttStats.SmoothedRTT()
+	tlpTimeout := utils.MaxDuration(2*srtt, srtt*3/2+maxAckDelay)
+	tlpTimeout = utils.MaxDuration(tlpTimeout, minTLPTimeout)
+
+	return h.lastSentRetransmittablePacketTime.Add(tlpTimeout)
+}
+
+// maybeSendTLP fires a tail loss probe when GetTLPTimeout has elapsed and
+// packetHistory is not empty, so a stalled tail of outstanding packets
+// doesn't have to wait for a full RTO before something is resent. An ACK
+// received since the last retransmittable send does not hold the probe
+// back unless it has emptied packetHistory. At most maxTLPs probes are sent
+// before maybeQueuePacketsRTO takes over.
+func (h *sentPacketHandler) maybeSendTLP() {
+	if h.packetHistory.Len() == 0 || h.tlpCount >= maxTLPs {
+		return
+	}
+
+	tlpTimeout := h.GetTLPTimeout()
+	if tlpTimeout.IsZero() || time.Now().Before(tlpTimeout) {
+		return
+	}
+
+	var probe *Packet
+	for p := h.lastSentPacketNumber; p > h.largestInOrderAcked; p-- {
+		if el, ok := h.packetHistoryIndex[p]; ok && !el.Value.Retransmitted && !el.Value.skippedPacket {
+			probe = el.Value
+			break
+		}
+	}
+
+	h.tlpCount++
+
+	if probe == nil {
+		// Nothing retransmittable is outstanding; ask the caller to send a
+		// fresh packet containing only a PING frame so the ACK clock keeps
+		// ticking. sentPacketHandler doesn't own packet-number allocation or
+		// the wire, so it can't synthesize and send the packet itself.
+		h.pingNeeded = true
+		log.Printf("TLP: no retransmittable packet outstanding, requesting ping (tlpCount %d)", h.tlpCount)
+		return
+	}
+
+	log.Printf("TLP: probing with packet %d (tlpCount %d)", probe.PacketNumber, h.tlpCount)
+	h.queuePacketForRetransmission(probe)
+}
+
+// ShouldSendPing reports whether maybeSendTLP wants a bare PING packet sent
+// because no retransmittable packet was outstanding to probe with. Calling
+// it clears the request, so it is only actioned once.
+func (h *sentPacketHandler) ShouldSendPing() bool {
+	needed := h.pingNeeded
+	h.pingNeeded = false
+	return needed
+}
+
 func (h *sentPacketHandler) getRTO() time.Duration {
 	rto := h.congestion.RetransmissionDelay()
 	if rto == 0 {